@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-02-01/network"
 
@@ -39,7 +40,8 @@ const (
 )
 
 var (
-	storageService = "Microsoft.Storage"
+	storageService       = "Microsoft.Storage"
+	storageServiceGlobal = "Microsoft.Storage.Global"
 )
 
 // getCloudProvider get Azure Cloud Provider
@@ -133,18 +135,24 @@ func getKubeClient(kubeconfig string) (*kubernetes.Clientset, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-func (d *Driver) updateSubnetServiceEndpoints(ctx context.Context) error {
-	if d.cloud.SubnetsClient == nil {
+func (d *Driver) updateSubnetServiceEndpoints(ctx context.Context) (err error) {
+	defer func() {
+		d.reportSubnetServiceEndpointCondition(ctx, err)
+	}()
+
+	cloud := d.cloud()
+	if cloud.SubnetsClient == nil {
 		return fmt.Errorf("SubnetsClient is nil")
 	}
 
-	resourceGroup := d.cloud.ResourceGroup
-	if len(d.cloud.VnetResourceGroup) > 0 {
-		resourceGroup = d.cloud.VnetResourceGroup
+	resourceGroup := cloud.ResourceGroup
+	if len(cloud.VnetResourceGroup) > 0 {
+		resourceGroup = cloud.VnetResourceGroup
 	}
-	location := d.cloud.Location
-	vnetName := d.cloud.VnetName
-	subnetName := d.cloud.SubnetName
+	resourceGroup = NormalizeResourceGroup(resourceGroup)
+	location := cloud.Location
+	vnetName := cloud.VnetName
+	subnetName := cloud.SubnetName
 
 	klog.V(2).Infof("updateSubnetServiceEndpoints on VnetName: %s, SubnetName: %s", vnetName, subnetName)
 
@@ -152,41 +160,86 @@ func (d *Driver) updateSubnetServiceEndpoints(ctx context.Context) error {
 	d.subnetLockMap.LockEntry(lockKey)
 	defer d.subnetLockMap.UnlockEntry(lockKey)
 
-	subnet, err := d.cloud.SubnetsClient.Get(ctx, resourceGroup, vnetName, subnetName, "")
+	// The client returns *retry.Error; assign it to the named error result only when it
+	// is non-nil, so a nil *retry.Error never reads as a non-nil error.
+	subnet, rerr := cloud.SubnetsClient.Get(ctx, resourceGroup, vnetName, subnetName, "")
+	if rerr != nil {
+		err = rerr
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get the subnet %s under vnet %s: %v", subnetName, vnetName, err)
 	}
-	endpointLocaions := []string{location}
-	storageServiceEndpoint := network.ServiceEndpointPropertiesFormat{
-		Service:   &storageService,
-		Locations: &endpointLocaions,
-	}
-	storageServiceExists := false
 	if subnet.SubnetPropertiesFormat == nil {
 		subnet.SubnetPropertiesFormat = &network.SubnetPropertiesFormat{}
 	}
 	if subnet.SubnetPropertiesFormat.ServiceEndpoints == nil {
 		subnet.SubnetPropertiesFormat.ServiceEndpoints = &[]network.ServiceEndpointPropertiesFormat{}
 	}
+
 	serviceEndpoints := *subnet.SubnetPropertiesFormat.ServiceEndpoints
-	for _, v := range serviceEndpoints {
-		if v.Service != nil && *v.Service == storageService {
-			storageServiceExists = true
-			klog.V(4).Infof("serviceEndpoint(%s) is already in subnet(%s)", storageService, subnetName)
+	needsUpdate := false
+	storageServiceExists := false
+	for i, v := range serviceEndpoints {
+		if !isStorageServiceEndpoint(v.Service) {
+			continue
+		}
+		storageServiceExists = true
+
+		var locations []string
+		if v.Locations != nil {
+			locations = *v.Locations
+		}
+		if locationsContain(locations, location) {
+			klog.V(4).Infof("serviceEndpoint(%s) already covers location(%s) in subnet(%s)", *v.Service, location, subnetName)
 			break
 		}
+
+		locations = append(locations, location)
+		serviceEndpoints[i].Locations = &locations
+		needsUpdate = true
+		klog.V(2).Infof("serviceEndpoint(%s) is extended with location(%s) in subnet(%s)", *v.Service, location, subnetName)
+		break
 	}
 
 	if !storageServiceExists {
-		serviceEndpoints = append(serviceEndpoints, storageServiceEndpoint)
+		serviceEndpoints = append(serviceEndpoints, network.ServiceEndpointPropertiesFormat{
+			Service:   &storageService,
+			Locations: &[]string{location},
+		})
+		needsUpdate = true
+		klog.V(2).Infof("serviceEndpoint(%s) is appended in subnet(%s)", storageService, subnetName)
+	}
+
+	if needsUpdate {
 		subnet.SubnetPropertiesFormat.ServiceEndpoints = &serviceEndpoints
 
-		err = d.cloud.SubnetsClient.CreateOrUpdate(context.Background(), resourceGroup, vnetName, subnetName, subnet)
+		if rerr := cloud.SubnetsClient.CreateOrUpdate(context.Background(), resourceGroup, vnetName, subnetName, subnet); rerr != nil {
+			err = rerr
+		}
 		if err != nil {
 			return fmt.Errorf("failed to update the subnet %s under vnet %s: %v", subnetName, vnetName, err)
 		}
-		klog.V(2).Infof("serviceEndpoint(%s) is appended in subnet(%s)", storageService, subnetName)
 	}
 
 	return nil
 }
+
+// isStorageServiceEndpoint reports whether service matches Microsoft.Storage, treating
+// the comparison as case-insensitive and accepting the expanded Microsoft.Storage.Global
+// form as an existing match so the driver doesn't churn out a duplicate endpoint.
+func isStorageServiceEndpoint(service *string) bool {
+	if service == nil {
+		return false
+	}
+	return strings.EqualFold(*service, storageService) || strings.EqualFold(*service, storageServiceGlobal)
+}
+
+// locationsContain reports whether locations already covers location, ignoring case.
+func locationsContain(locations []string, location string) bool {
+	for _, l := range locations {
+		if strings.EqualFold(l, location) {
+			return true
+		}
+	}
+	return false
+}
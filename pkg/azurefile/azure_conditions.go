@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/azurefile-csi-driver/pkg/azurefile/conditions"
+)
+
+// reportCloudConfigCondition publishes CloudConfigLoaded after a getCloudProvider call,
+// so operators can alert on the driver running without cloud config instead of grepping
+// logs for "driver will run without cloud config". It no-ops if the driver has no
+// conditions.Writer configured (no kubeClient was available) or no nodeID
+// (the controller plugin doesn't own a CSINode object).
+func (d *Driver) reportCloudConfigCondition(ctx context.Context, err error) {
+	if d.conditionsWriter == nil || d.NodeID == "" {
+		return
+	}
+
+	cond := conditions.NewCondition(conditions.CloudConfigLoaded, corev1.ConditionTrue, "", "", metav1.Now())
+	if err != nil {
+		cond = conditions.NewCondition(conditions.CloudConfigLoaded, corev1.ConditionFalse, cloudConfigLoadErrorReason(err), err.Error(), metav1.Now())
+	}
+
+	if werr := d.conditionsWriter.SetCondition(ctx, d.NodeID, cond); werr != nil {
+		klog.Errorf("reportCloudConfigCondition: failed to publish CloudConfigLoaded condition: %v", werr)
+	}
+}
+
+// cloudConfigLoadErrorReason maps a getCloudProvider error to ReasonCredentialFileUnreadable
+// when the failure happened opening/parsing the on-disk credential file, and to
+// ReasonSecretMissing for every other getCloudProvider failure (no KubeClient, or the
+// cloud-config Secret itself couldn't be read), since that's the only signal
+// getCloudProvider's plain error return currently carries.
+func cloudConfigLoadErrorReason(err error) string {
+	if strings.Contains(err.Error(), "load azure config from file") {
+		return conditions.ReasonCredentialFileUnreadable
+	}
+	return conditions.ReasonSecretMissing
+}
+
+// reportSubnetServiceEndpointCondition publishes SubnetServiceEndpointReady after an
+// updateSubnetServiceEndpoints attempt, with reason SubnetUpdateForbidden surfaced
+// explicitly since a 403 from SubnetsClient (SPN missing
+// Microsoft.Network/virtualNetworks/subnets/write) is by far the most common failure.
+func (d *Driver) reportSubnetServiceEndpointCondition(ctx context.Context, err error) {
+	if d.conditionsWriter == nil || d.NodeID == "" {
+		return
+	}
+
+	cond := conditions.NewCondition(conditions.SubnetServiceEndpointReady, corev1.ConditionTrue, "", "", metav1.Now())
+	if err != nil {
+		reason := conditions.ReasonSubnetUpdateForbidden
+		if !isForbiddenError(err) {
+			reason = ""
+		}
+		cond = conditions.NewCondition(conditions.SubnetServiceEndpointReady, corev1.ConditionFalse, reason, err.Error(), metav1.Now())
+	}
+
+	if werr := d.conditionsWriter.SetCondition(ctx, d.NodeID, cond); werr != nil {
+		klog.Errorf("reportSubnetServiceEndpointCondition: failed to publish SubnetServiceEndpointReady condition: %v", werr)
+	}
+}
+
+// reportMountHelpersCondition publishes MountHelpersAvailable after a probeMountHelpers
+// call from NodeGetInfo.
+func (d *Driver) reportMountHelpersCondition(ctx context.Context, err error) {
+	if d.conditionsWriter == nil || d.NodeID == "" {
+		return
+	}
+
+	cond := conditions.NewCondition(conditions.MountHelpersAvailable, corev1.ConditionTrue, "", "", metav1.Now())
+	if err != nil {
+		cond = conditions.NewCondition(conditions.MountHelpersAvailable, corev1.ConditionFalse, "MountHelperProbeFailed", err.Error(), metav1.Now())
+	}
+
+	if werr := d.conditionsWriter.SetCondition(ctx, d.NodeID, cond); werr != nil {
+		klog.Errorf("reportMountHelpersCondition: failed to publish MountHelpersAvailable condition: %v", werr)
+	}
+}
+
+// reportStorageAccountReachableCondition publishes StorageAccountReachable after a
+// NodeStageVolume mount attempt. A failed mount.cifs call is the closest observable
+// signal this driver has to "can the node actually reach the storage account", short of
+// a dedicated connectivity check, so it's used as the proxy here.
+func (d *Driver) reportStorageAccountReachableCondition(ctx context.Context, err error) {
+	if d.conditionsWriter == nil || d.NodeID == "" {
+		return
+	}
+
+	cond := conditions.NewCondition(conditions.StorageAccountReachable, corev1.ConditionTrue, "", "", metav1.Now())
+	if err != nil {
+		cond = conditions.NewCondition(conditions.StorageAccountReachable, corev1.ConditionFalse, "MountFailed", err.Error(), metav1.Now())
+	}
+
+	if werr := d.conditionsWriter.SetCondition(ctx, d.NodeID, cond); werr != nil {
+		klog.Errorf("reportStorageAccountReachableCondition: failed to publish StorageAccountReachable condition: %v", werr)
+	}
+}
+
+// isForbiddenError reports whether err looks like an Azure 403 response. The
+// SubnetsClient wraps the underlying autorest error in its own *retry.Error before
+// updateSubnetServiceEndpoints turns it into a plain error, so this is a best-effort
+// string match rather than a type assertion.
+func isForbiddenError(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "Forbidden"))
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	azureprovider "sigs.k8s.io/cloud-provider-azure/pkg/provider"
+)
+
+// credentialRotationTotal counts successful in-place reloads of the Azure cloud
+// provider config, broken down by the source that triggered the rotation.
+var credentialRotationTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "azurefile_csi_driver_credential_rotation_total",
+		Help: "Number of times the driver rebuilt its Azure cloud provider config after a secret or credential file rotation",
+	},
+	[]string{"source"},
+)
+
+func init() {
+	prometheus.MustRegister(credentialRotationTotal)
+}
+
+// credentialWatcher watches the cloud-config Secret (and, optionally, the on-disk
+// credential file) for changes and rebuilds the driver's azureprovider.Cloud in place,
+// so that SPN secret, client certificate, or MSI rotations take effect without a CSI
+// pod restart.
+type credentialWatcher struct {
+	d *Driver
+
+	kubeconfig      string
+	nodeID          string
+	secretName      string
+	secretNamespace string
+	userAgent       string
+	credFile        string
+
+	recorder record.EventRecorder
+	// podRef is the object rotation events are emitted against, so "kubectl describe
+	// pod" on the actual running driver pod shows them. It is built from the POD_NAME/
+	// POD_NAMESPACE downward-API env vars the DaemonSet/Deployment spec sets, since a
+	// generated pod name can't be hardcoded at build time.
+	podRef *v1.ObjectReference
+
+	mu            sync.Mutex
+	lastAzureJSON string
+}
+
+// newCredentialWatcher creates a credentialWatcher for the driver's configured cloud-config
+// Secret and credential file. It does not start watching until Run is called.
+func newCredentialWatcher(d *Driver, kubeconfig, nodeID, secretName, secretNamespace, userAgent, credFile string, recorder record.EventRecorder, podRef *v1.ObjectReference) *credentialWatcher {
+	return &credentialWatcher{
+		d:               d,
+		kubeconfig:      kubeconfig,
+		nodeID:          nodeID,
+		secretName:      secretName,
+		secretNamespace: secretNamespace,
+		userAgent:       userAgent,
+		credFile:        credFile,
+		recorder:        recorder,
+		podRef:          podRef,
+	}
+}
+
+// Run starts watching for credential rotations until ctx is cancelled. The Secret watch
+// is mandatory; the on-disk credential file is only watched via fsnotify if it exists.
+func (w *credentialWatcher) Run(ctx context.Context) {
+	go w.watchSecret(ctx)
+	if w.credFile != "" {
+		go w.watchCredFile(ctx)
+	}
+}
+
+func (w *credentialWatcher) watchSecret(ctx context.Context) {
+	if w.d.kubeClient == nil {
+		klog.V(2).Infof("credentialWatcher: no kubeClient available, skipping Secret watch for %s/%s", w.secretNamespace, w.secretName)
+		return
+	}
+
+	for {
+		watcher, err := w.d.kubeClient.CoreV1().Secrets(w.secretNamespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", w.secretName).String(),
+		})
+		if err != nil {
+			klog.Errorf("credentialWatcher: failed to watch secret %s/%s: %v", w.secretNamespace, w.secretName, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(30 * time.Second):
+				continue
+			}
+		}
+		w.consumeSecretEvents(ctx, watcher)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (w *credentialWatcher) consumeSecretEvents(ctx context.Context, watcher watch.Interface) {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			if event.Type != watch.Modified && event.Type != watch.Added {
+				continue
+			}
+			secret, ok := event.Object.(*v1.Secret)
+			if !ok {
+				continue
+			}
+			klog.V(2).Infof("credentialWatcher: detected change on secret %s/%s, rebuilding cloud provider", w.secretNamespace, w.secretName)
+			w.reload(ctx, "secret", secret.ResourceVersion)
+		}
+	}
+}
+
+func (w *credentialWatcher) watchCredFile(ctx context.Context) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		klog.Errorf("credentialWatcher: failed to create fsnotify watcher for %s: %v", w.credFile, err)
+		return
+	}
+	defer fsWatcher.Close()
+
+	if err := fsWatcher.Add(w.credFile); err != nil {
+		klog.Errorf("credentialWatcher: failed to watch credential file %s: %v", w.credFile, err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			klog.V(2).Infof("credentialWatcher: detected change on credential file %s, rebuilding cloud provider", w.credFile)
+			w.reload(ctx, "file", "")
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			klog.Errorf("credentialWatcher: fsnotify error watching %s: %v", w.credFile, err)
+		}
+	}
+}
+
+// reload rebuilds the Azure cloud provider from scratch and, on success, swaps it into
+// the driver and records the rotation. version identifies the triggering Secret's
+// resourceVersion so duplicate watch events for the same content are skipped.
+func (w *credentialWatcher) reload(ctx context.Context, source, version string) {
+	w.mu.Lock()
+	if source == "secret" && version != "" && version == w.lastAzureJSON {
+		w.mu.Unlock()
+		return
+	}
+	if source == "secret" {
+		w.lastAzureJSON = version
+	}
+	w.mu.Unlock()
+
+	az, err := getCloudProvider(w.kubeconfig, w.nodeID, w.secretName, w.secretNamespace, w.userAgent)
+	w.d.reportCloudConfigCondition(ctx, err)
+	if err != nil {
+		klog.Errorf("credentialWatcher: failed to rebuild cloud provider after %s rotation: %v", source, err)
+		w.emitEvent(v1.EventTypeWarning, "CredentialRotationFailed", fmt.Sprintf("failed to reload Azure cloud config after %s change: %v", source, err))
+		return
+	}
+
+	w.d.setCloud(az)
+	credentialRotationTotal.WithLabelValues(source).Inc()
+	w.emitEvent(v1.EventTypeNormal, "CredentialRotated", fmt.Sprintf("reloaded Azure cloud config after %s change", source))
+	klog.V(2).Infof("credentialWatcher: cloud provider reloaded successfully after %s rotation", source)
+}
+
+func (w *credentialWatcher) emitEvent(eventType, reason, message string) {
+	if w.recorder == nil || w.podRef == nil || w.podRef.Name == "" {
+		return
+	}
+	w.recorder.Eventf(w.podRef, eventType, reason, message)
+}
+
+// cloud returns the driver's current Azure cloud provider. It is guarded by cloudMu so
+// that a rotation in progress never hands back a partially-rebuilt *azureprovider.Cloud
+// to a concurrent CreateVolume/NodeStage call.
+func (d *Driver) cloud() *azureprovider.Cloud {
+	d.cloudMu.RLock()
+	defer d.cloudMu.RUnlock()
+	return d.azureCloud
+}
+
+// setCloud swaps in a freshly rebuilt Azure cloud provider, e.g. after the cloud-config
+// Secret or credential file has been rotated.
+func (d *Driver) setCloud(az *azureprovider.Cloud) {
+	d.cloudMu.Lock()
+	defer d.cloudMu.Unlock()
+	d.azureCloud = az
+}
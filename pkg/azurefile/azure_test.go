@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import "testing"
+
+func TestIsStorageServiceEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *string
+		want    bool
+	}{
+		{name: "nil service", service: nil, want: false},
+		{name: "exact match", service: stringPtr("Microsoft.Storage"), want: true},
+		{name: "mixed case match", service: stringPtr("microsoft.STORAGE"), want: true},
+		{name: "expanded global form", service: stringPtr("Microsoft.Storage.Global"), want: true},
+		{name: "mixed case global form", service: stringPtr("MICROSOFT.storage.global"), want: true},
+		{name: "unrelated service", service: stringPtr("Microsoft.Sql"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStorageServiceEndpoint(tt.service); got != tt.want {
+				t.Errorf("isStorageServiceEndpoint(%v) = %v, want %v", tt.service, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocationsContain(t *testing.T) {
+	tests := []struct {
+		name      string
+		locations []string
+		location  string
+		want      bool
+	}{
+		{name: "empty locations", locations: nil, location: "eastus", want: false},
+		{name: "exact match", locations: []string{"eastus", "westus"}, location: "eastus", want: true},
+		{name: "mixed case match", locations: []string{"EastUS", "WestUS"}, location: "eastus", want: true},
+		{name: "multi-region, no match", locations: []string{"westus", "westus2", "northeurope"}, location: "eastus", want: false},
+		{name: "multi-region, case-insensitive match on later entry", locations: []string{"westus", "EASTUS", "northeurope"}, location: "eastus", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := locationsContain(tt.locations, tt.location); got != tt.want {
+				t.Errorf("locationsContain(%v, %q) = %v, want %v", tt.locations, tt.location, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/azurefile-csi-driver/pkg/azurefile/conditions"
+)
+
+// DriverOptions are the flags the node/controller command plumbs into NewDriver.
+type DriverOptions struct {
+	NodeID                     string
+	Kubeconfig                 string
+	CloudConfigSecretName      string
+	CloudConfigSecretNamespace string
+	UserAgent                  string
+	CredFile                   string
+
+	// PodName and PodNamespace identify the driver's own pod, read from the POD_NAME/
+	// POD_NAMESPACE downward-API env vars by the node/controller command. They're the
+	// target of credential-rotation Events, so operators watching the actual running
+	// pod see them. Rotation events are skipped if PodName is empty.
+	PodName      string
+	PodNamespace string
+
+	// StartupTaint is the taint key the node command removes from its own Node object
+	// once NodeGetInfo first reports success. Empty disables the feature.
+	StartupTaint string
+
+	// EnableGuestDiagnostics opts the node plugin into collecting in-guest SMB mount
+	// diagnostics on NodeStageVolume failures, for PVCs that also carry
+	// guestDiagnosticsAnnotation. NodeUnstageVolume failures are not covered.
+	EnableGuestDiagnostics bool
+}
+
+// NewDriver builds the cloud provider, then starts the driver's background controllers
+// (currently: the credential hot-reload watcher) before returning.
+func NewDriver(ctx context.Context, options *DriverOptions) (*Driver, error) {
+	az, err := getCloudProvider(options.Kubeconfig, options.NodeID, options.CloudConfigSecretName, options.CloudConfigSecretNamespace, options.UserAgent)
+
+	d := &Driver{
+		NodeID:                 options.NodeID,
+		startupTaint:           options.StartupTaint,
+		enableGuestDiagnostics: options.EnableGuestDiagnostics,
+		subnetLockMap:          newLockMap(),
+	}
+	d.setCloud(az)
+	if az != nil {
+		d.kubeClient = az.KubeClient
+	}
+	if d.kubeClient != nil {
+		d.eventRecorder = newEventRecorder(d.kubeClient, "azurefile-csi-driver")
+		d.conditionsWriter = conditions.NewCSINodeWriter(d.kubeClient)
+	}
+	d.reportCloudConfigCondition(ctx, err)
+
+	podRef := &v1.ObjectReference{Kind: "Pod", Name: options.PodName, Namespace: options.PodNamespace}
+	watcher := newCredentialWatcher(d, options.Kubeconfig, options.NodeID, options.CloudConfigSecretName, options.CloudConfigSecretNamespace, options.UserAgent, options.CredFile, d.eventRecorder, podRef)
+	watcher.Run(ctx)
+
+	return d, nil
+}
+
+// newEventRecorder builds an EventRecorder that publishes to the API server, shared by
+// the credential watcher and guest-diagnostics collection so both surface through the
+// same component name.
+func newEventRecorder(kubeClient kubernetes.Interface, component string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conditions publishes typed readiness conditions for the Azure File CSI driver,
+// in the style of CAPZ's ScaleSetDesiredReplicasCondition/ScaleSetModelUpdatedCondition,
+// so operators can alert on driver health instead of grepping logs.
+package conditions
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Type identifies a specific readiness condition the driver reports.
+type Type string
+
+const (
+	// CloudConfigLoaded reports whether the Azure cloud-config Secret or credential file
+	// was successfully loaded into an azureprovider.Cloud.
+	CloudConfigLoaded Type = "CloudConfigLoaded"
+
+	// SubnetServiceEndpointReady reports whether the driver was able to ensure the
+	// Microsoft.Storage service endpoint on the configured subnet.
+	SubnetServiceEndpointReady Type = "SubnetServiceEndpointReady"
+
+	// StorageAccountReachable reports whether the driver could reach the storage
+	// account used for the most recent NodeStageVolume mount.
+	StorageAccountReachable Type = "StorageAccountReachable"
+
+	// MountHelpersAvailable reports whether the mount.cifs helper is present and
+	// runnable on the node; mount.nfs is not probed.
+	MountHelpersAvailable Type = "MountHelpersAvailable"
+)
+
+// Common reasons set alongside a False condition.
+const (
+	// ReasonSecretMissing is set on CloudConfigLoaded when the cloud-config Secret
+	// could not be found.
+	ReasonSecretMissing = "SecretMissing"
+	// ReasonCredentialFileUnreadable is set on CloudConfigLoaded when neither the
+	// Secret nor the on-disk credential file could be read.
+	ReasonCredentialFileUnreadable = "CredentialFileUnreadable"
+	// ReasonSubnetUpdateForbidden is set on SubnetServiceEndpointReady when the
+	// SubnetsClient call returns 403, typically because the SPN lacks
+	// Microsoft.Network/virtualNetworks/subnets/write.
+	ReasonSubnetUpdateForbidden = "SubnetUpdateForbidden"
+)
+
+// Condition is a single point-in-time readiness signal for one subsystem.
+type Condition struct {
+	Type               Type
+	Status             corev1.ConditionStatus
+	Reason             string
+	Message            string
+	LastTransitionTime metav1.Time
+}
+
+// Writer publishes Conditions for a node's driver instance. Implementations target the
+// driver's CSINode/CSIDriver status or a dedicated CR; Writer exists as an interface so
+// callers can fake it in tests instead of depending on a live API server.
+type Writer interface {
+	// SetCondition upserts cond for nodeName, replacing any existing condition of the
+	// same Type.
+	SetCondition(ctx context.Context, nodeName string, cond Condition) error
+}
+
+// NewCondition builds a Condition with LastTransitionTime defaulted by the caller; it's a
+// small convenience so call sites don't repeat the same struct literal.
+func NewCondition(t Type, status corev1.ConditionStatus, reason, message string, now metav1.Time) Condition {
+	return Condition{
+		Type:               t,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: now,
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// conditionsAnnotation is where conditions are stashed on the node's CSINode object.
+// storage.k8s.io/v1 CSINode has no generic status.conditions field, so the driver
+// round-trips a small JSON blob through an annotation rather than standing up a CRD.
+const conditionsAnnotation = "csi.azurefile.com/conditions"
+
+// CSINodeWriter publishes Conditions onto the azurefile CSINodeDriver's owning CSINode
+// object, keyed by node name.
+type CSINodeWriter struct {
+	kubeClient kubernetes.Interface
+}
+
+// NewCSINodeWriter returns a Writer backed by the given kubeClient.
+func NewCSINodeWriter(kubeClient kubernetes.Interface) *CSINodeWriter {
+	return &CSINodeWriter{kubeClient: kubeClient}
+}
+
+var _ Writer = &CSINodeWriter{}
+
+// SetCondition upserts cond into the conditions annotation on the CSINode named nodeName.
+//
+// Two conditions can legitimately be reported concurrently by different goroutines (e.g.
+// CloudConfigLoaded from the credential-watcher racing SubnetServiceEndpointReady from a
+// volume call), and both would otherwise read the same stale annotation and each write
+// back a blob containing only their own update - whichever Patch landed second would
+// silently clobber the other condition. Get-modify-Update under the object's
+// ResourceVersion instead, retrying on conflict, so neither update is lost.
+func (w *CSINodeWriter) SetCondition(ctx context.Context, nodeName string, cond Condition) error {
+	backoff := wait.Backoff{
+		Duration: 100 * time.Millisecond,
+		Factor:   2,
+		Steps:    5,
+	}
+
+	return wait.ExponentialBackoff(backoff, func() (bool, error) {
+		csiNode, err := w.kubeClient.StorageV1().CSINodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get CSINode %s: %v", nodeName, err)
+		}
+
+		existing, err := decodeConditions(csiNode.Annotations[conditionsAnnotation])
+		if err != nil {
+			return false, fmt.Errorf("failed to decode existing conditions on CSINode %s: %v", nodeName, err)
+		}
+
+		updated := upsertCondition(existing, cond)
+		encoded, err := json.Marshal(updated)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode conditions for CSINode %s: %v", nodeName, err)
+		}
+
+		csiNode = csiNode.DeepCopy()
+		if csiNode.Annotations == nil {
+			csiNode.Annotations = map[string]string{}
+		}
+		csiNode.Annotations[conditionsAnnotation] = string(encoded)
+
+		if _, err := w.kubeClient.StorageV1().CSINodes().Update(ctx, csiNode, metav1.UpdateOptions{}); err != nil {
+			if apierrors.IsConflict(err) {
+				klog.V(2).Infof("SetCondition: conflict updating CSINode %s, retrying: %v", nodeName, err)
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to update CSINode %s: %v", nodeName, err)
+		}
+		return true, nil
+	})
+}
+
+func decodeConditions(raw string) ([]Condition, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var conds []Condition
+	if err := json.Unmarshal([]byte(raw), &conds); err != nil {
+		return nil, err
+	}
+	return conds, nil
+}
+
+func upsertCondition(conds []Condition, cond Condition) []Condition {
+	for i := range conds {
+		if conds[i].Type == cond.Type {
+			conds[i] = cond
+			return conds
+		}
+	}
+	return append(conds, cond)
+}
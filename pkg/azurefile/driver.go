@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	azureprovider "sigs.k8s.io/cloud-provider-azure/pkg/provider"
+
+	"sigs.k8s.io/azurefile-csi-driver/pkg/azurefile/conditions"
+)
+
+// Driver implements the CSI node and controller gRPC servers for Azure File.
+type Driver struct {
+	// NodeID is this node's Kubernetes Node name on the node plugin; it is empty on the
+	// controller plugin.
+	NodeID string
+
+	// startupTaint is the taint key NodeGetInfo removes from this node once the node
+	// plugin first reports ready. Empty disables the feature.
+	startupTaint string
+
+	// enableGuestDiagnostics opts NodeStageVolume mount failures into collecting in-guest
+	// SMB diagnostics, for PVCs that also carry guestDiagnosticsAnnotation.
+	// NodeUnstageVolume failures are not covered.
+	enableGuestDiagnostics bool
+
+	// kubeClient talks to the API server for Node/Secret/CSINode/PVC/Event access. It is
+	// nil when the driver couldn't build one, e.g. running outside a cluster.
+	kubeClient kubernetes.Interface
+
+	// eventRecorder publishes Kubernetes Events for credential rotation and guest
+	// diagnostics. It is nil whenever kubeClient is nil.
+	eventRecorder record.EventRecorder
+
+	// conditionsWriter publishes per-subsystem readiness Conditions onto this node's
+	// CSINode object. It is nil whenever kubeClient is nil.
+	conditionsWriter conditions.Writer
+
+	// cloudMu guards azureCloud so a credential rotation in progress never hands a
+	// partially-rebuilt *azureprovider.Cloud to a concurrent CreateVolume/NodeStage call.
+	cloudMu    sync.RWMutex
+	azureCloud *azureprovider.Cloud
+
+	// subnetLockMap serializes updateSubnetServiceEndpoints calls per
+	// resourceGroup+vnet+subnet key.
+	subnetLockMap *lockMap
+}
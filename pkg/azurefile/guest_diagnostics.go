@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2020-12-01/compute"
+
+	"k8s.io/klog/v2"
+)
+
+// guestDiagnosticsAnnotation, when set to "true" on the PVC, opts that volume's node
+// into in-guest SMB mount diagnostics collection on NodeStageVolume failures
+// (NodeUnstageVolume failures are not covered). It requires --enable-guest-diagnostics
+// on the node plugin.
+const guestDiagnosticsAnnotation = "csi.azurefile.com/enable-guest-diagnostics"
+
+// guestDiagnosticsScript is run via RunCommand on the node VM (or VMSS VM) that owns the
+// failed mount, so operators get dmesg/mount.cifs/syslog context without needing a
+// separate SSH session.
+const guestDiagnosticsScript = `dmesg | tail -n 200; mount.cifs -V; tail -n 200 /var/log/messages 2>/dev/null || tail -n 200 /var/log/syslog 2>/dev/null`
+
+// guestDiagnosticsTarget identifies the VM (or VMSS VM) that owns a failed mount.
+// VMSSName is empty for a VM-backed (availability-set) node pool, in which case VMName
+// is looked up directly through VirtualMachinesClient.
+type guestDiagnosticsTarget struct {
+	ResourceGroup string
+	VMSSName      string
+	InstanceID    string
+	VMName        string
+}
+
+// collectGuestDiagnostics runs guestDiagnosticsScript on the node identified by target and
+// returns its combined output for attaching to a CSI Event on the PVC. It is a no-op
+// unless the driver was started with --enable-guest-diagnostics and the PVC carries
+// guestDiagnosticsAnnotation.
+func (d *Driver) collectGuestDiagnostics(ctx context.Context, pvcAnnotations map[string]string, target guestDiagnosticsTarget) (string, error) {
+	if !d.enableGuestDiagnostics {
+		return "", nil
+	}
+	if pvcAnnotations[guestDiagnosticsAnnotation] != "true" {
+		return "", nil
+	}
+
+	cloud := d.cloud()
+	resourceGroup := NormalizeResourceGroup(target.ResourceGroup)
+	parameters := compute.RunCommandInput{
+		CommandID: stringPtr("RunShellScript"),
+		Script:    &[]string{guestDiagnosticsScript},
+	}
+
+	// VMSS-backed node pools (the default AKS topology) only expose VMSSVMClient; a VM
+	// is only reachable through VirtualMachinesClient when it isn't part of a scale set.
+	// Never fall through to the other client when the selected one is nil.
+	if target.VMSSName != "" {
+		if cloud.VMSSVMClient == nil {
+			return "", fmt.Errorf("no VMSSVMClient available to run guest diagnostics")
+		}
+		klog.V(2).Infof("collectGuestDiagnostics: running diagnostics script on VMSS(%s) instance(%s) in resource group(%s)", target.VMSSName, target.InstanceID, resourceGroup)
+		result, rerr := cloud.VMSSVMClient.RunCommand(ctx, resourceGroup, target.VMSSName, target.InstanceID, parameters)
+		if rerr != nil {
+			return "", fmt.Errorf("failed to run guest diagnostics on VMSS(%s) instance(%s): %v", target.VMSSName, target.InstanceID, rerr.Error())
+		}
+		return formatRunCommandResult(result), nil
+	}
+
+	if cloud.VirtualMachinesClient == nil {
+		return "", fmt.Errorf("no VirtualMachinesClient available to run guest diagnostics")
+	}
+	klog.V(2).Infof("collectGuestDiagnostics: running diagnostics script on VM(%s) in resource group(%s)", target.VMName, resourceGroup)
+	result, rerr := cloud.VirtualMachinesClient.RunCommand(ctx, resourceGroup, target.VMName, parameters)
+	if rerr != nil {
+		return "", fmt.Errorf("failed to run guest diagnostics on VM(%s): %v", target.VMName, rerr.Error())
+	}
+
+	return formatRunCommandResult(result), nil
+}
+
+func formatRunCommandResult(result compute.RunCommandResult) string {
+	if result.Value == nil {
+		return ""
+	}
+	var out string
+	for _, status := range *result.Value {
+		if status.Message != nil {
+			out += *status.Message + "\n"
+		}
+	}
+	return out
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
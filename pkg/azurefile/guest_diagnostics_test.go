@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2020-12-01/compute"
+	"github.com/golang/mock/gomock"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmclient/mockvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
+	azureprovider "sigs.k8s.io/cloud-provider-azure/pkg/provider"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestCollectGuestDiagnostics_DisabledByDefault(t *testing.T) {
+	d := &Driver{}
+	out, err := d.collectGuestDiagnostics(context.Background(), map[string]string{guestDiagnosticsAnnotation: "true"}, guestDiagnosticsTarget{})
+	if err != nil || out != "" {
+		t.Fatalf("collectGuestDiagnostics() = (%q, %v), want (\"\", nil) when enableGuestDiagnostics is false", out, err)
+	}
+}
+
+func TestCollectGuestDiagnostics_MissingAnnotation(t *testing.T) {
+	d := &Driver{enableGuestDiagnostics: true}
+	out, err := d.collectGuestDiagnostics(context.Background(), nil, guestDiagnosticsTarget{})
+	if err != nil || out != "" {
+		t.Fatalf("collectGuestDiagnostics() = (%q, %v), want (\"\", nil) when the PVC lacks guestDiagnosticsAnnotation", out, err)
+	}
+}
+
+func TestCollectGuestDiagnostics_VMSS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().
+		RunCommand(gomock.Any(), "my-rg", "my-vmss", "3", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, _, _ string, params compute.RunCommandInput) (compute.RunCommandResult, *retry.Error) {
+			if params.CommandID == nil || *params.CommandID != "RunShellScript" {
+				t.Fatalf("RunCommand called with unexpected CommandID: %+v", params.CommandID)
+			}
+			if params.Script == nil || len(*params.Script) != 1 || (*params.Script)[0] != guestDiagnosticsScript {
+				t.Fatalf("RunCommand called with unexpected Script: %+v", params.Script)
+			}
+			msg := "dmesg output"
+			return compute.RunCommandResult{Value: &[]compute.InstanceViewStatus{{Message: &msg}}}, nil
+		})
+
+	d := &Driver{enableGuestDiagnostics: true}
+	d.setCloud(&azureprovider.Cloud{VMSSVMClient: mockVMSSVMClient})
+
+	target := guestDiagnosticsTarget{ResourceGroup: "My-RG", VMSSName: "my-vmss", InstanceID: "3"}
+	out, err := d.collectGuestDiagnostics(context.Background(), map[string]string{guestDiagnosticsAnnotation: "true"}, target)
+	if err != nil {
+		t.Fatalf("collectGuestDiagnostics() error = %v", err)
+	}
+	if want := "dmesg output\n"; out != want {
+		t.Errorf("collectGuestDiagnostics() = %q, want %q", out, want)
+	}
+}
+
+func TestCollectGuestDiagnostics_VM(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMClient := mockvmclient.NewMockInterface(ctrl)
+	mockVMClient.EXPECT().
+		RunCommand(gomock.Any(), "my-rg", "my-vm", gomock.Any()).
+		Return(compute.RunCommandResult{}, nil)
+
+	d := &Driver{enableGuestDiagnostics: true}
+	d.setCloud(&azureprovider.Cloud{VirtualMachinesClient: mockVMClient})
+
+	target := guestDiagnosticsTarget{ResourceGroup: "my-rg", VMName: "my-vm"}
+	out, err := d.collectGuestDiagnostics(context.Background(), map[string]string{guestDiagnosticsAnnotation: "true"}, target)
+	if err != nil {
+		t.Fatalf("collectGuestDiagnostics() error = %v", err)
+	}
+	if out != "" {
+		t.Errorf("collectGuestDiagnostics() = %q, want empty", out)
+	}
+}
+
+func TestCollectGuestDiagnostics_VMSSWithoutVMSSVMClient(t *testing.T) {
+	d := &Driver{enableGuestDiagnostics: true}
+	d.setCloud(&azureprovider.Cloud{})
+
+	target := guestDiagnosticsTarget{ResourceGroup: "my-rg", VMSSName: "my-vmss", InstanceID: "0"}
+	if _, err := d.collectGuestDiagnostics(context.Background(), map[string]string{guestDiagnosticsAnnotation: "true"}, target); err == nil {
+		t.Fatal("collectGuestDiagnostics() error = nil, want error when VMSSVMClient is nil")
+	}
+}
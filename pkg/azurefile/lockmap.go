@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import "sync"
+
+// lockMap serializes operations keyed by an arbitrary string (e.g. a resource group/vnet/
+// subnet tuple) without blocking operations against unrelated keys.
+type lockMap struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// newLockMap returns an empty lockMap.
+func newLockMap() *lockMap {
+	return &lockMap{locks: make(map[string]*sync.Mutex)}
+}
+
+// LockEntry locks the mutex associated with key, creating it on first use.
+func (l *lockMap) LockEntry(key string) {
+	l.mu.Lock()
+	entry, ok := l.locks[key]
+	if !ok {
+		entry = &sync.Mutex{}
+		l.locks[key] = entry
+	}
+	l.mu.Unlock()
+	entry.Lock()
+}
+
+// UnlockEntry unlocks the mutex associated with key.
+func (l *lockMap) UnlockEntry(key string) {
+	l.mu.Lock()
+	entry, ok := l.locks[key]
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+	entry.Unlock()
+}
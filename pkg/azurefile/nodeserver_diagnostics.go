@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// These match the keys the external-provisioner/external-resizer sidecars set on
+// VolumeContext when run with --extra-create-metadata, which is how the node plugin
+// learns which PVC a given NodeStageVolume/NodeUnstageVolume call is for.
+const (
+	pvcNameContextKey      = "csi.storage.k8s.io/pvc/name"
+	pvcNamespaceContextKey = "csi.storage.k8s.io/pvc/namespace"
+)
+
+// protocolContextKey selects the share protocol for NodeStageVolume. Any value other than
+// nfsProtocol mounts via mount.cifs.
+const (
+	protocolContextKey = "protocol"
+	nfsProtocol        = "nfs"
+)
+
+// Secret keys the external-provisioner hands NodeStageVolume when the StorageClass
+// references a node-stage-secret-ref backed by a storage account key Secret.
+const (
+	accountNameSecretKey = "azurestorageaccountname"
+	accountKeySecretKey  = "azurestorageaccountkey"
+)
+
+// NodeStageVolume mounts the Azure File share identified by the volume context's "source"
+// onto req.GetStagingTargetPath(). It is idempotent: if the staging path is already
+// mounted (kubelet re-issues NodeStageVolume across kubelet/pod restarts) it returns
+// immediately. On mount failure it calls handleMountFailureDiagnostics before returning
+// the original mount error.
+func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	source := req.GetVolumeContext()["source"]
+	target := req.GetStagingTargetPath()
+	if source == "" {
+		return nil, fmt.Errorf("NodeStageVolume: source is empty in volume context")
+	}
+	if target == "" {
+		return nil, fmt.Errorf("NodeStageVolume: staging target path is empty")
+	}
+
+	mounted, err := isMounted(target)
+	if err != nil {
+		return nil, fmt.Errorf("NodeStageVolume: failed to check mount state of %s: %v", target, err)
+	}
+	if mounted {
+		klog.V(2).Infof("NodeStageVolume: %s is already mounted, skipping", target)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	if err := os.MkdirAll(target, 0750); err != nil {
+		return nil, fmt.Errorf("NodeStageVolume: failed to create staging target %s: %v", target, err)
+	}
+
+	mountErr := mountAzureFile(ctx, source, target, req.GetVolumeContext(), req.GetSecrets(), mountFlags(req.GetVolumeCapability()))
+	d.reportStorageAccountReachableCondition(ctx, mountErr)
+	if mountErr != nil {
+		d.handleMountFailureDiagnostics(ctx, req.GetVolumeContext(), mountErr)
+		return nil, mountErr
+	}
+
+	return &csi.NodeStageVolumeResponse{}, nil
+}
+
+// NodeUnstageVolume unmounts req.GetStagingTargetPath(). It is idempotent: an already-
+// unmounted staging path is a no-op, matching the CSI spec's retry semantics. On unmount
+// failure it calls handleMountFailureDiagnostics without a volume context, so no PVC is
+// identified and no guest diagnostics are collected for unstage failures.
+func (d *Driver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	target := req.GetStagingTargetPath()
+	if target == "" {
+		return nil, fmt.Errorf("NodeUnstageVolume: staging target path is empty")
+	}
+
+	mounted, err := isMounted(target)
+	if err != nil {
+		return nil, fmt.Errorf("NodeUnstageVolume: failed to check mount state of %s: %v", target, err)
+	}
+	if !mounted {
+		klog.V(2).Infof("NodeUnstageVolume: %s is not mounted, skipping", target)
+		return &csi.NodeUnstageVolumeResponse{}, nil
+	}
+
+	if out, err := exec.CommandContext(ctx, "umount", target).CombinedOutput(); err != nil {
+		unmountErr := fmt.Errorf("umount %s failed: %v (%s)", target, err, out)
+		d.handleMountFailureDiagnostics(ctx, nil, unmountErr)
+		return nil, unmountErr
+	}
+
+	return &csi.NodeUnstageVolumeResponse{}, nil
+}
+
+// mountFlags extracts the CSI-requested mount flags for a Mount volume capability, e.g. set
+// by a StorageClass's mountOptions.
+func mountFlags(volCap *csi.VolumeCapability) []string {
+	if mnt := volCap.GetMount(); mnt != nil {
+		return mnt.GetMountFlags()
+	}
+	return nil
+}
+
+// mountAzureFile mounts the share at source onto target, via mount.cifs by default or
+// mount -t nfs when volumeContext[protocolContextKey] is nfsProtocol. For CIFS, the
+// storage account name/key NodeStageVolume received through req.GetSecrets() (the
+// StorageClass's nodeStageSecretRef) are passed through as username/password mount
+// options.
+func mountAzureFile(ctx context.Context, source, target string, volumeContext, secrets map[string]string, extraFlags []string) error {
+	options := append([]string{}, extraFlags...)
+
+	if volumeContext[protocolContextKey] == nfsProtocol {
+		args := append([]string{"-t", "nfs"}, mountArgs(source, target, options)...)
+		if out, err := exec.CommandContext(ctx, "mount", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("mount -t nfs %s at %s failed: %v (%s)", source, target, err, out)
+		}
+		return nil
+	}
+
+	if accountName := secrets[accountNameSecretKey]; accountName != "" {
+		options = append(options, fmt.Sprintf("username=%s", accountName), fmt.Sprintf("password=%s", secrets[accountKeySecretKey]))
+	}
+	if out, err := exec.CommandContext(ctx, "mount.cifs", mountArgs(source, target, options)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("mount.cifs %s at %s failed: %v (%s)", source, target, err, out)
+	}
+	return nil
+}
+
+func mountArgs(source, target string, options []string) []string {
+	args := []string{source, target}
+	if len(options) > 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	return args
+}
+
+// isMounted reports whether target is already an active mount point, by scanning
+// /proc/mounts rather than shelling out, so it works without an extra mount-utils binary.
+func isMounted(target string) (bool, error) {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == target {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// handleMountFailureDiagnostics looks up the PVC behind volumeContext (if any) and, only
+// if the driver has --enable-guest-diagnostics enabled and that PVC carries
+// guestDiagnosticsAnnotation, runs collectGuestDiagnostics and attaches the output to a
+// CSI Event on the PVC. Reading the PVC here is what makes the annotation check
+// RBAC-gated: without "get" on persistentvolumeclaims the lookup fails closed and
+// diagnostics are skipped, regardless of the annotation's value.
+func (d *Driver) handleMountFailureDiagnostics(ctx context.Context, volumeContext map[string]string, mountErr error) {
+	if !d.enableGuestDiagnostics || d.kubeClient == nil {
+		return
+	}
+
+	pvcName := volumeContext[pvcNameContextKey]
+	pvcNamespace := volumeContext[pvcNamespaceContextKey]
+	if pvcName == "" || pvcNamespace == "" {
+		return
+	}
+
+	pvc, err := d.kubeClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		klog.V(2).Infof("handleMountFailureDiagnostics: cannot read PVC %s/%s, skipping guest diagnostics: %v", pvcNamespace, pvcName, err)
+		return
+	}
+
+	target := guestDiagnosticsTarget{
+		ResourceGroup: d.cloud().ResourceGroup,
+		VMSSName:      volumeContext["vmssName"],
+		InstanceID:    volumeContext["instanceID"],
+		VMName:        d.NodeID,
+	}
+
+	output, err := d.collectGuestDiagnostics(ctx, pvc.Annotations, target)
+	if err != nil {
+		klog.Errorf("handleMountFailureDiagnostics: failed to collect guest diagnostics for PVC %s/%s: %v", pvcNamespace, pvcName, err)
+		return
+	}
+	if output == "" {
+		return
+	}
+
+	d.emitPVCEvent(pvc, v1.EventTypeWarning, "MountFailedDiagnostics", fmt.Sprintf("mount failed (%v); guest diagnostics:\n%s", mountErr, output))
+}
+
+func (d *Driver) emitPVCEvent(pvc *v1.PersistentVolumeClaim, eventType, reason, message string) {
+	if d.eventRecorder == nil {
+		return
+	}
+	d.eventRecorder.Eventf(pvc, eventType, reason, message)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+
+	"k8s.io/klog/v2"
+)
+
+// NodeGetInfo returns the node's topology info. Once the node's mount helpers have been
+// probed successfully, it removes the driver's startup taint (if --startup-taint was
+// set), so pods aren't scheduled onto the node before the Azure File node plugin is
+// actually ready to serve NodeStageVolume. Borrowed from the EBS CSI driver's
+// startup-taint pattern.
+func (d *Driver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	probeErr := probeMountHelpers()
+	d.reportMountHelpersCondition(ctx, probeErr)
+	if probeErr != nil {
+		klog.Errorf("NodeGetInfo: mount helper probe failed: %v", probeErr)
+		return nil, fmt.Errorf("mount helper probe failed: %v", probeErr)
+	}
+
+	if d.kubeClient != nil && d.startupTaint != "" {
+		if err := removeStartupTaint(ctx, d.kubeClient, d.NodeID, d.startupTaint); err != nil {
+			klog.Errorf("NodeGetInfo: failed to remove startup taint %s from node %s: %v", d.startupTaint, d.NodeID, err)
+		}
+	}
+
+	return &csi.NodeGetInfoResponse{
+		NodeId: d.NodeID,
+	}, nil
+}
+
+// probeMountHelpers confirms the SMB mount helper (mount.cifs) is present and runnable
+// before the node plugin reports ready. The NFS mount helper is not probed.
+func probeMountHelpers() error {
+	if _, err := exec.LookPath("mount.cifs"); err != nil {
+		return fmt.Errorf("mount.cifs not found: %v", err)
+	}
+	if out, err := exec.Command("mount.cifs", "-V").CombinedOutput(); err != nil {
+		return fmt.Errorf("mount.cifs -V failed: %v (%s)", err, out)
+	}
+	return nil
+}
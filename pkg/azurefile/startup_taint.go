@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// removeStartupTaint removes the taint named taintKey from the node named nodeName, once
+// the node plugin has finished bootstrapping (cloud config loaded, kubelet plugin
+// registered, and the relevant mount helpers probed). It mirrors the EBS CSI driver's
+// startup-taint pattern so pods aren't scheduled onto a node before the Azure File node
+// plugin is actually ready to serve NodeStageVolume.
+//
+// It no-ops if taintKey is empty or the node has no taint with that key.
+func removeStartupTaint(ctx context.Context, kubeClient kubernetes.Interface, nodeName, taintKey string) error {
+	if taintKey == "" {
+		return nil
+	}
+
+	backoff := wait.Backoff{
+		Duration: 100 * time.Millisecond,
+		Factor:   2,
+		Steps:    5,
+	}
+
+	return wait.ExponentialBackoff(backoff, func() (bool, error) {
+		node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, err
+			}
+			klog.Warningf("removeStartupTaint: failed to get node %s, retrying: %v", nodeName, err)
+			return false, nil
+		}
+
+		remaining := make([]v1.Taint, 0, len(node.Spec.Taints))
+		found := false
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == taintKey {
+				found = true
+				continue
+			}
+			remaining = append(remaining, taint)
+		}
+		if !found {
+			klog.V(2).Infof("removeStartupTaint: node %s has no taint with key %s, nothing to do", nodeName, taintKey)
+			return true, nil
+		}
+
+		// v1.NodeSpec.Taints is patchStrategy:"merge" patchMergeKey:"key", so a strategic-
+		// merge patch merges the submitted taints by key rather than replacing the list -
+		// it can never actually remove an entry simply by omitting it. A JSON merge patch
+		// replaces the list wholesale instead, and carrying the ResourceVersion read above
+		// makes the server reject a stale write with a conflict this loop retries against
+		// a fresh Get.
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"resourceVersion": node.ResourceVersion},
+			"spec":     map[string]interface{}{"taints": remaining},
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to build patch for node %s: %v", nodeName, err)
+		}
+		if _, err := kubeClient.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			if apierrors.IsConflict(err) {
+				klog.V(2).Infof("removeStartupTaint: conflict patching node %s, retrying: %v", nodeName, err)
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to patch node %s to remove taint %s: %v", nodeName, taintKey, err)
+		}
+
+		klog.V(2).Infof("removeStartupTaint: removed taint %s from node %s", taintKey, nodeName)
+		return true, nil
+	})
+}
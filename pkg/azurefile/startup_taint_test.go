@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRemoveStartupTaint(t *testing.T) {
+	tests := []struct {
+		name          string
+		taintKey      string
+		initialTaints []v1.Taint
+		wantTaints    []v1.Taint
+	}{
+		{
+			name:          "empty taint key is a no-op",
+			taintKey:      "",
+			initialTaints: []v1.Taint{{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoSchedule}},
+			wantTaints:    []v1.Taint{{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoSchedule}},
+		},
+		{
+			name:          "absent taint key is a no-op",
+			taintKey:      "csi.azurefile.com/agent-not-ready",
+			initialTaints: []v1.Taint{{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoSchedule}},
+			wantTaints:    []v1.Taint{{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoSchedule}},
+		},
+		{
+			name:     "removes only the matching taint, preserving others",
+			taintKey: "csi.azurefile.com/agent-not-ready",
+			initialTaints: []v1.Taint{
+				{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoSchedule},
+				{Key: "csi.azurefile.com/agent-not-ready", Effect: v1.TaintEffectNoSchedule},
+				{Key: "csi.azurefile.com/agent-not-ready", Effect: v1.TaintEffectNoExecute},
+			},
+			wantTaints: []v1.Taint{
+				{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoSchedule},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+				Spec:       v1.NodeSpec{Taints: tt.initialTaints},
+			}
+			client := fake.NewSimpleClientset(node)
+
+			if err := removeStartupTaint(context.Background(), client, "node-0", tt.taintKey); err != nil {
+				t.Fatalf("removeStartupTaint() error = %v", err)
+			}
+
+			got, err := client.CoreV1().Nodes().Get(context.Background(), "node-0", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get node: %v", err)
+			}
+			if len(got.Spec.Taints) != len(tt.wantTaints) {
+				t.Fatalf("got %d taints, want %d: %+v", len(got.Spec.Taints), len(tt.wantTaints), got.Spec.Taints)
+			}
+			for i, taint := range tt.wantTaints {
+				if got.Spec.Taints[i].Key != taint.Key || got.Spec.Taints[i].Effect != taint.Effect {
+					t.Errorf("taint[%d] = %+v, want %+v", i, got.Spec.Taints[i], taint)
+				}
+			}
+		})
+	}
+}
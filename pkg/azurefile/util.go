@@ -0,0 +1,41 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import "strings"
+
+// NormalizeResourceGroup lowercases the resource group name in rg. Azure treats resource
+// group names case-insensitively, but the driver threads user-supplied ResourceGroup/
+// VnetResourceGroup strings (e.g. from StorageClass parameters) straight into
+// SubnetsClient/VM/VMSS client calls and into lock and cache keys, so mixed casing between
+// azure.json and a StorageClass can otherwise cause the same resource group to be treated
+// as two different keys.
+//
+// rg may be either a raw resource group name or a full Azure resource ID
+// (/subscriptions/<subID>/resourceGroups/<rg>/...). For a full resource ID, only the
+// resourceGroups path segment is lowercased, so the subscription ID, provider namespace,
+// and resource name segments keep their original casing.
+func NormalizeResourceGroup(rg string) string {
+	segments := strings.Split(rg, "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if strings.EqualFold(segments[i], "resourceGroups") {
+			segments[i+1] = strings.ToLower(segments[i+1])
+			return strings.Join(segments, "/")
+		}
+	}
+	return strings.ToLower(rg)
+}
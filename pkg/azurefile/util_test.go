@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurefile
+
+import "testing"
+
+func TestNormalizeResourceGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		rg   string
+		want string
+	}{
+		{name: "already lowercase", rg: "my-rg", want: "my-rg"},
+		{name: "all uppercase, as azure.json might store it", rg: "MY-RG", want: "my-rg"},
+		{name: "mixed case, as a StorageClass parameter might set it", rg: "My-Rg", want: "my-rg"},
+		{name: "empty string", rg: "", want: ""},
+		{
+			name: "full resource ID, mixed case resource group segment",
+			rg:   "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/My-RG/providers/Microsoft.Network/virtualNetworks/myVnet",
+			want: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/my-rg/providers/Microsoft.Network/virtualNetworks/myVnet",
+		},
+		{
+			name: "full resource ID, lowercase resourceGroups path segment keyword",
+			rg:   "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/My-RG/providers/Microsoft.Network/virtualNetworks/myVnet",
+			want: "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/my-rg/providers/Microsoft.Network/virtualNetworks/myVnet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeResourceGroup(tt.rg); got != tt.want {
+				t.Errorf("NormalizeResourceGroup(%q) = %q, want %q", tt.rg, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeResourceGroup_PreservesOtherSegmentCasing guards against the blanket
+// strings.ToLower(rg) regression: for a full resource ID, only the resourceGroups segment
+// may change case, since the subscription ID, provider namespace, and resource name
+// segments are not resource group names and Azure does treat some of those
+// case-sensitively.
+func TestNormalizeResourceGroup_PreservesOtherSegmentCasing(t *testing.T) {
+	id := "/subscriptions/AAAA0000-0000-0000-0000-000000000000/resourceGroups/My-RG/providers/Microsoft.Network/virtualNetworks/MyVnet"
+	got := NormalizeResourceGroup(id)
+	want := "/subscriptions/AAAA0000-0000-0000-0000-000000000000/resourceGroups/my-rg/providers/Microsoft.Network/virtualNetworks/MyVnet"
+	if got != want {
+		t.Errorf("NormalizeResourceGroup(%q) = %q, want %q", id, got, want)
+	}
+}
+
+// TestNormalizeResourceGroup_SameKeyRegardlessOfCasing guards the actual bug report: a
+// StorageClass parameter and azure.json disagreeing on resource group casing must still
+// resolve to the same lock/cache key.
+func TestNormalizeResourceGroup_SameKeyRegardlessOfCasing(t *testing.T) {
+	fromAzureJSON := NormalizeResourceGroup("MC_myRG_aks_eastus")
+	fromStorageClass := NormalizeResourceGroup("mc_myrg_aks_eastus")
+
+	if fromAzureJSON != fromStorageClass {
+		t.Errorf("normalized resource groups diverge: %q vs %q", fromAzureJSON, fromStorageClass)
+	}
+}
@@ -58,4 +58,8 @@ type Interface interface {
 
 	// Delete deletes a VirtualMachine.
 	Delete(ctx context.Context, resourceGroupName string, VMName string) *retry.Error
+
+	// RunCommand runs a command against a VirtualMachine and returns its output, e.g.
+	// for in-guest diagnostics collection.
+	RunCommand(ctx context.Context, resourceGroupName string, VMName string, parameters compute.RunCommandInput) (compute.RunCommandResult, *retry.Error)
 }
@@ -149,3 +149,18 @@ func (mr *MockInterfaceMockRecorder) Delete(ctx, resourceGroupName, VMName inter
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockInterface)(nil).Delete), ctx, resourceGroupName, VMName)
 }
+
+// RunCommand mocks base method
+func (m *MockInterface) RunCommand(ctx context.Context, resourceGroupName, VMName string, parameters compute.RunCommandInput) (compute.RunCommandResult, *retry.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunCommand", ctx, resourceGroupName, VMName, parameters)
+	ret0, _ := ret[0].(compute.RunCommandResult)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// RunCommand indicates an expected call of RunCommand
+func (mr *MockInterfaceMockRecorder) RunCommand(ctx, resourceGroupName, VMName, parameters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunCommand", reflect.TypeOf((*MockInterface)(nil).RunCommand), ctx, resourceGroupName, VMName, parameters)
+}
@@ -135,3 +135,18 @@ func (mr *MockInterfaceMockRecorder) UpdateVMs(ctx, resourceGroupName, VMScaleSe
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVMs", reflect.TypeOf((*MockInterface)(nil).UpdateVMs), ctx, resourceGroupName, VMScaleSetName, instances, source)
 }
+
+// RunCommand mocks base method
+func (m *MockInterface) RunCommand(ctx context.Context, resourceGroupName, VMScaleSetName, instanceID string, parameters compute.RunCommandInput) (compute.RunCommandResult, *retry.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunCommand", ctx, resourceGroupName, VMScaleSetName, instanceID, parameters)
+	ret0, _ := ret[0].(compute.RunCommandResult)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// RunCommand indicates an expected call of RunCommand
+func (mr *MockInterfaceMockRecorder) RunCommand(ctx, resourceGroupName, VMScaleSetName, instanceID, parameters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunCommand", reflect.TypeOf((*MockInterface)(nil).RunCommand), ctx, resourceGroupName, VMScaleSetName, instanceID, parameters)
+}